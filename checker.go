@@ -1,9 +1,13 @@
 package letsdebug
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"runtime/debug"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // ValidationMethod represents an ACME validation method
@@ -16,93 +20,237 @@ const (
 	TLSSNI02 ValidationMethod = "tls-sni-02" // TLSSNI02 represents the ACME tls-sni-02 validation method.
 )
 
+// checkerTimeout bounds how long a single checker may run before it's
+// cancelled. It's a var, not a const, so tests can shrink it.
+var checkerTimeout = 30 * time.Second
+
+// SeverityInternal indicates a checker failure (e.g. a panic), not a problem with the domain.
+const SeverityInternal Severity = "Internal"
+
 var (
 	validMethods     = map[ValidationMethod]bool{HTTP01: true, DNS01: true, TLSSNI01: true, TLSSNI02: true}
 	errNotApplicable = errors.New("Checker not applicable for this domain and method")
-	checkers         []checker
 )
 
 func init() {
-	checkers = []checker{
-		// show stopping checkers
+	for _, c := range []Checker{
 		validMethodChecker{},
 		validDomainChecker{},
 		tlssni0102DisabledChecker{},
 		wildcardDns01OnlyChecker{},
 		caaChecker{},
-
-		// others
 		dnsAChecker{},
+	} {
+		RegisterChecker(c, PhaseShowstopper)
+	}
 
-		asyncCheckerBlock{
-			httpAccessibilityChecker{},
-			cloudflareChecker{},
-			statusioChecker{},
-			txtRecordChecker{},
-			&rateLimitChecker{},
-		},
+	for _, c := range []Checker{
+		httpAccessibilityChecker{},
+		cloudflareChecker{},
+		statusioChecker{},
+		txtRecordChecker{},
+		&rateLimitChecker{},
+	} {
+		RegisterChecker(c, PhaseParallel)
 	}
 }
 
-type checker interface {
-	Check(ctx *scanContext, domain string, method ValidationMethod) ([]Problem, error)
+// Checker is implemented by anything that can inspect domain for problems
+// that would prevent an ACME validation method from succeeding. Register
+// one with RegisterChecker to add it to the scan pipeline.
+type Checker interface {
+	Check(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error)
 }
 
-// asyncCheckerBlock represents a checker which is composed of other checkers that can be run simultaneously.
-type asyncCheckerBlock []checker
+// Check performs a full scan of domain for method.
+func Check(domain string, method ValidationMethod) ([]Problem, error) {
+	return CheckWithContext(context.Background(), domain, method)
+}
 
-func (c asyncCheckerBlock) Check(ctx *scanContext, domain string, method ValidationMethod) ([]Problem, error) {
-	// waitgroup for all the checker goroutines
-	var wg sync.WaitGroup
-	wg.Add(len(c))
+// CheckWithContext is Check bounded by ctx, for aborting a slow scan.
+func CheckWithContext(ctx context.Context, domain string, method ValidationMethod) ([]Problem, error) {
+	sc := newScanContext()
 
-	// error channel which either
-	// - signals either the waitgroup is done (nil error)
-	// - signals a checker has encountered an error (shortcut other checkers)
-	errChan := make(chan error, len(c))
+	var probs []Problem
 
-	go func() {
-		wg.Wait()
-		errChan <- nil
+	// runSequential applies the same per-checker timeout asyncCheckerBlock
+	// gives its members, so a sequential checker (e.g. dnsAChecker) can't
+	// hang a scan forever on an unresponsive resolver.
+	runSequential := func(c Checker) error {
+		return runSequentialChecker(ctx, c, func(p Problem) {
+			probs = append(probs, p)
+		}, func(checkerCtx context.Context) error {
+			checkerProbs, chkErr := c.Check(checkerCtx, sc, domain, method)
+			if len(checkerProbs) > 0 {
+				probs = append(probs, checkerProbs...)
+			}
+			if chkErr != nil && chkErr != errNotApplicable {
+				return chkErr
+			}
+			return nil
+		})
+	}
+
+	showstoppers, parallel, post := registeredCheckers()
+
+	for _, c := range showstoppers {
+		if err := runSequential(c); err != nil {
+			return probs, err
+		}
+	}
+	if len(parallel) > 0 {
+		if err := ctx.Err(); err != nil {
+			return probs, err
+		}
+		checkerProbs, err := parallel.Check(ctx, sc, domain, method)
+		if len(checkerProbs) > 0 {
+			probs = append(probs, checkerProbs...)
+		}
+		if err != nil && err != errNotApplicable {
+			return probs, err
+		}
+	}
+	for _, c := range post {
+		if err := runSequential(c); err != nil {
+			return probs, err
+		}
+	}
+
+	return probs, nil
+}
+
+// runSequentialChecker applies the per-checker timeout and panic recovery
+// shared by CheckWithContext's and CheckStream's sequential (showstopper and
+// post) phases: it bounds c's run with checkerTimeout, and recovers a panic
+// into a Problem handed to report rather than letting it crash the scan.
+// run does the actual checking against checkerCtx and is responsible for
+// delivering any Problems it produces and filtering errNotApplicable.
+func runSequentialChecker(ctx context.Context, c Checker, report func(Problem), run func(checkerCtx context.Context) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	checkerCtx, cancel := context.WithTimeout(ctx, checkerTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			report(internalPanicProblem(c, r, debug.Stack()))
+			err = nil
+		}
 	}()
 
-	// channel to which any problems encountered in each checker are written
+	return run(checkerCtx)
+}
+
+// internalPanicProblem converts a recovered panic from chk into a Problem.
+func internalPanicProblem(chk Checker, r interface{}, stack []byte) Problem {
+	name := fmt.Sprintf("%T", chk)
+	return Problem{
+		Name:        name,
+		Explanation: fmt.Sprintf("The %s checker panicked and could not complete.", name),
+		Detail:      fmt.Sprintf("panic: %v\n%s", r, stack),
+		Severity:    SeverityInternal,
+	}
+}
+
+// asyncCheckerBlock represents a checker which is composed of other checkers that can be run simultaneously.
+type asyncCheckerBlock []Checker
+
+func (c asyncCheckerBlock) Check(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+	g, gctx := errgroup.WithContext(ctx)
+
+	// buffered so a checker finishing after the group has already failed never blocks sending
 	resultsChan := make(chan []Problem, len(c))
 
-	// launch each goroutine
 	for _, currentChecker := range c {
-		go func(chk checker) {
+		currentChecker := currentChecker
+
+		g.Go(func() (err error) {
+			// Recover into a Problem rather than failing the whole group.
 			defer func() {
 				if r := recover(); r != nil {
-					errChan <- fmt.Errorf("panic: %v", r)
-				} else {
-					wg.Done()
+					resultsChan <- []Problem{internalPanicProblem(currentChecker, r, debug.Stack())}
 				}
 			}()
-			probs, err := chk.Check(ctx, domain, method)
-			if err != nil && err != errNotApplicable {
-				errChan <- err
-				return
+
+			checkerCtx, cancel := context.WithTimeout(gctx, checkerTimeout)
+			defer cancel()
+
+			release, err := pool.acquireFor(checkerCtx, currentChecker)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			probs, chkErr := currentChecker.Check(checkerCtx, sc, domain, method)
+			if chkErr != nil && chkErr != errNotApplicable {
+				return chkErr
 			}
 			resultsChan <- probs
-		}(currentChecker)
+			return nil
+		})
 	}
 
+	err := g.Wait()
+	close(resultsChan)
+
 	var probs []Problem
+	for checkerProbs := range resultsChan {
+		if len(checkerProbs) > 0 {
+			probs = append(probs, checkerProbs...)
+		}
+	}
 
-	for i := 0; i < len(c); i++ {
-		select {
-		case checkerProbs := <-resultsChan:
-			// store any results
-			if len(checkerProbs) > 0 {
-				probs = append(probs, checkerProbs...)
+	return probs, err
+}
+
+// CheckStream is the streaming counterpart to Check: it emits each member
+// checker's Problems to out as soon as that checker completes.
+func (c asyncCheckerBlock) CheckStream(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod, out chan<- Problem) error {
+	g, gctx := errgroup.WithContext(ctx)
+
+	for _, currentChecker := range c {
+		currentChecker := currentChecker
+
+		g.Go(func() (err error) {
+			checkerCtx, cancel := context.WithTimeout(gctx, checkerTimeout)
+			defer cancel()
+
+			defer func() {
+				if r := recover(); r != nil {
+					// Use gctx, not checkerCtx, so a consumer that's merely
+					// slow near this checker's own deadline isn't mistaken
+					// for an abandoned one.
+					sendProblem(gctx, out, internalPanicProblem(currentChecker, r, debug.Stack()))
+				}
+			}()
+
+			release, err := pool.acquireFor(checkerCtx, currentChecker)
+			if err != nil {
+				return err
 			}
+			defer release()
 
-		case err := <-errChan:
-			// short circuit exit
-			return probs, err
-		}
+			if streaming, ok := currentChecker.(streamingChecker); ok {
+				if chkErr := streaming.CheckStream(checkerCtx, sc, domain, method, out); chkErr != nil && chkErr != errNotApplicable {
+					return chkErr
+				}
+				return nil
+			}
+
+			probs, chkErr := currentChecker.Check(checkerCtx, sc, domain, method)
+			if chkErr != nil && chkErr != errNotApplicable {
+				return chkErr
+			}
+			for _, p := range probs {
+				if sendErr := sendProblem(gctx, out, p); sendErr != nil {
+					return sendErr
+				}
+			}
+			return nil
+		})
 	}
 
-	return probs, nil
+	return g.Wait()
 }
@@ -0,0 +1,110 @@
+package letsdebug
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeChecker struct {
+	fn func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error)
+}
+
+func (f fakeChecker) Check(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+	return f.fn(ctx, sc, domain, method)
+}
+
+func TestAsyncCheckerBlockRecoversPanic(t *testing.T) {
+	block := asyncCheckerBlock{
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			panic("boom")
+		}},
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			return nil, nil
+		}},
+	}
+
+	probs, err := block.Check(context.Background(), newScanContext(), "example.com", HTTP01)
+	if err != nil {
+		t.Fatalf("expected no error from a recovered panic, got %v", err)
+	}
+	if len(probs) != 1 || probs[0].Severity != SeverityInternal {
+		t.Fatalf("expected one SeverityInternal problem, got %+v", probs)
+	}
+}
+
+func TestCheckWithContextTimesOutSequentialCheckers(t *testing.T) {
+	orig := checkerTimeout
+	checkerTimeout = 20 * time.Millisecond
+	defer func() { checkerTimeout = orig }()
+
+	withCleanRegistry(t, func() {
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}}, PhaseShowstopper)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := CheckWithContext(context.Background(), "example.com", HTTP01)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Fatal("expected the per-checker timeout to abort a hanging showstopper checker")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("CheckWithContext did not return once its sequential checker's timeout elapsed")
+		}
+	})
+}
+
+func TestCheckWithContextRecoversShowstopperPanic(t *testing.T) {
+	withCleanRegistry(t, func() {
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			panic("boom")
+		}}, PhaseShowstopper)
+
+		probs, err := CheckWithContext(context.Background(), "example.com", HTTP01)
+		if err != nil {
+			t.Fatalf("expected no error from a recovered showstopper panic, got %v", err)
+		}
+		if len(probs) != 1 || probs[0].Severity != SeverityInternal {
+			t.Fatalf("expected one SeverityInternal problem, got %+v", probs)
+		}
+	})
+}
+
+func TestAsyncCheckerBlockContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	block := asyncCheckerBlock{
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := block.Check(ctx, newScanContext(), "example.com", HTTP01)
+		done <- err
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error after the scan context was cancelled")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Check did not return after context cancellation")
+	}
+}
@@ -0,0 +1,197 @@
+package letsdebug
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultMaxConcurrency is the pool size used when SetMaxConcurrency hasn't been called.
+const defaultMaxConcurrency = 8
+
+// defaultPerHostConcurrency bounds how many checkers may run at once against the same remote host.
+const defaultPerHostConcurrency = 2
+
+var pool = newWorkerPool(defaultMaxConcurrency)
+
+// PoolStats is a point-in-time snapshot of the shared checker worker pool's
+// load, for embedders that want to expose it via their own metrics.
+type PoolStats struct {
+	Queued  int
+	Running int
+}
+
+// SetMaxConcurrency changes how many checkers may run simultaneously across
+// all in-flight scans. It is safe to call while scans are in progress; the
+// new limit is picked up as slots are next acquired.
+func SetMaxConcurrency(n int) {
+	pool.resize(n)
+}
+
+// Stats returns a snapshot of the shared checker worker pool's load.
+func Stats() PoolStats {
+	return pool.stats()
+}
+
+// hostSlot is a per-host semaphore, refcounted so workerPool can evict it
+// once nothing holds it rather than keeping one channel per host forever.
+type hostSlot struct {
+	sem  chan struct{}
+	refs int
+}
+
+// poolGeneration is the global semaphore backing one "version" of a
+// workerPool's capacity, plus the queued/running counts for that semaphore
+// specifically. resize swaps in a new generation rather than mutating
+// queued/running in place, so slots acquired against a since-replaced
+// generation are counted against that old generation when released, not
+// against the pool's current capacity.
+type poolGeneration struct {
+	sem     chan struct{}
+	queued  int
+	running int
+}
+
+// workerPool bounds how many checkers may run at once, both globally and
+// per remote host, so a server scanning many domains concurrently doesn't
+// fan out unboundedly against a single resolver or API.
+type workerPool struct {
+	mu  sync.Mutex
+	gen *poolGeneration
+
+	hosts map[string]*hostSlot
+}
+
+func newWorkerPool(n int) *workerPool {
+	if n <= 0 {
+		n = defaultMaxConcurrency
+	}
+	return &workerPool{
+		gen:   &poolGeneration{sem: make(chan struct{}, n)},
+		hosts: make(map[string]*hostSlot),
+	}
+}
+
+// resize swaps in a new generation of capacity n. Slots already acquired
+// against the old generation are unaffected and release into the old
+// generation's own counters, so Stats() reflects only the current
+// generation and Running never exceeds its capacity.
+func (p *workerPool) resize(n int) {
+	if n <= 0 {
+		n = defaultMaxConcurrency
+	}
+	p.mu.Lock()
+	p.gen = &poolGeneration{sem: make(chan struct{}, n)}
+	p.mu.Unlock()
+}
+
+func (p *workerPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{Queued: p.gen.queued, Running: p.gen.running}
+}
+
+// acquire blocks until a global pool slot is free or ctx is cancelled,
+// returning a release func to be called (at most once) when the work is
+// done.
+func (p *workerPool) acquire(ctx context.Context) (release func(), err error) {
+	p.mu.Lock()
+	gen := p.gen
+	gen.queued++
+	p.mu.Unlock()
+
+	select {
+	case gen.sem <- struct{}{}:
+		p.mu.Lock()
+		gen.queued--
+		gen.running++
+		p.mu.Unlock()
+
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				p.mu.Lock()
+				gen.running--
+				p.mu.Unlock()
+				<-gen.sem
+			})
+		}, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		gen.queued--
+		p.mu.Unlock()
+		return func() {}, ctx.Err()
+	}
+}
+
+// remoteHostChecker is implemented by checkers that hit one shared remote
+// endpoint (the Cloudflare API, status.io, an authoritative nameserver),
+// so the pool can additionally rate-limit concurrency against that host.
+type remoteHostChecker interface {
+	RemoteHost() string
+}
+
+// acquireFor acquires a global pool slot for chk, plus a per-host slot if
+// chk implements remoteHostChecker, returning a single release func that
+// releases whichever slots were taken.
+func (p *workerPool) acquireFor(ctx context.Context, chk Checker) (release func(), err error) {
+	releaseGlobal, err := p.acquire(ctx)
+	if err != nil {
+		return func() {}, err
+	}
+
+	rhc, ok := chk.(remoteHostChecker)
+	if !ok {
+		return releaseGlobal, nil
+	}
+
+	releaseHost, err := p.acquireHost(ctx, rhc.RemoteHost())
+	if err != nil {
+		releaseGlobal()
+		return func() {}, err
+	}
+
+	return func() {
+		releaseHost()
+		releaseGlobal()
+	}, nil
+}
+
+// acquireHost blocks until a per-host slot for host is free or ctx is
+// cancelled, returning a release func. It composes with acquire: callers
+// typically hold both a global and a host slot for one outbound request.
+func (p *workerPool) acquireHost(ctx context.Context, host string) (release func(), err error) {
+	p.mu.Lock()
+	slot, ok := p.hosts[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, defaultPerHostConcurrency)}
+		p.hosts[host] = slot
+	}
+	slot.refs++
+	p.mu.Unlock()
+
+	release = func() {
+		var once sync.Once
+		once.Do(func() {
+			<-slot.sem
+			p.mu.Lock()
+			slot.refs--
+			if slot.refs == 0 && p.hosts[host] == slot {
+				delete(p.hosts, host)
+			}
+			p.mu.Unlock()
+		})
+	}
+
+	select {
+	case slot.sem <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		slot.refs--
+		if slot.refs == 0 && p.hosts[host] == slot {
+			delete(p.hosts, host)
+		}
+		p.mu.Unlock()
+		return func() {}, ctx.Err()
+	}
+}
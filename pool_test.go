@@ -0,0 +1,214 @@
+package letsdebug
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type hostChecker struct {
+	fakeChecker
+	host string
+}
+
+func (h hostChecker) RemoteHost() string { return h.host }
+
+func TestWorkerPoolAcquireForEvictsHostSlot(t *testing.T) {
+	p := newWorkerPool(defaultMaxConcurrency)
+
+	release, err := p.acquireFor(context.Background(), hostChecker{host: "example.com"})
+	if err != nil {
+		t.Fatalf("acquireFor: %v", err)
+	}
+
+	p.mu.Lock()
+	if _, ok := p.hosts["example.com"]; !ok {
+		p.mu.Unlock()
+		t.Fatal("expected a host slot to be tracked while held")
+	}
+	p.mu.Unlock()
+
+	release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.hosts["example.com"]; ok {
+		t.Fatal("expected the host slot to be evicted once released")
+	}
+}
+
+// runThroughPool acquires a slot for each of the n checkers concurrently,
+// holds it for a short while, and returns the highest number observed
+// running at once.
+func runThroughPool(t *testing.T, p *workerPool, checkers []Checker) int32 {
+	t.Helper()
+
+	var current, max int32
+	var wg sync.WaitGroup
+	for _, chk := range checkers {
+		chk := chk
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := p.acquireFor(context.Background(), chk)
+			if err != nil {
+				t.Errorf("acquireFor: %v", err)
+				return
+			}
+			defer release()
+
+			n := atomic.AddInt32(&current, 1)
+			for {
+				old := atomic.LoadInt32(&max)
+				if n <= old || atomic.CompareAndSwapInt32(&max, old, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		}()
+	}
+	wg.Wait()
+	return max
+}
+
+func TestWorkerPoolCapsGlobalConcurrency(t *testing.T) {
+	const cap = 2
+	p := newWorkerPool(cap)
+
+	// each checker gets a distinct host, so only the global semaphore is exercised
+	checkers := make([]Checker, 6)
+	for i := range checkers {
+		checkers[i] = hostChecker{host: "host" + string(rune('a'+i))}
+	}
+
+	if max := runThroughPool(t, p, checkers); max > cap {
+		t.Fatalf("expected at most %d checkers running at once, observed %d", cap, max)
+	}
+}
+
+func TestWorkerPoolCapsPerHostConcurrency(t *testing.T) {
+	p := newWorkerPool(defaultMaxConcurrency)
+
+	checkers := make([]Checker, defaultPerHostConcurrency+3)
+	for i := range checkers {
+		checkers[i] = hostChecker{host: "shared.example.com"}
+	}
+
+	if max := runThroughPool(t, p, checkers); max > defaultPerHostConcurrency {
+		t.Fatalf("expected at most %d checkers running against the same host at once, observed %d", defaultPerHostConcurrency, max)
+	}
+}
+
+func TestWorkerPoolStats(t *testing.T) {
+	p := newWorkerPool(1)
+
+	release1, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release2, err := p.acquire(context.Background())
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+			return
+		}
+		// Release before signaling, so the main goroutine's post-signal
+		// stats() check can't observe this slot as still held.
+		release2()
+		close(acquired)
+	}()
+
+	// give the second acquire a moment to start queueing behind the first
+	time.Sleep(20 * time.Millisecond)
+	if stats := p.stats(); stats.Running != 1 || stats.Queued != 1 {
+		t.Fatalf("expected one running and one queued slot, got %+v", stats)
+	}
+
+	release1()
+
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued acquire never unblocked after release")
+	}
+
+	if stats := p.stats(); stats.Running != 0 || stats.Queued != 0 {
+		t.Fatalf("expected the pool to be idle once both slots released, got %+v", stats)
+	}
+}
+
+func TestWorkerPoolSetMaxConcurrencyTakesEffectForFutureAcquires(t *testing.T) {
+	p := newWorkerPool(1)
+
+	release1, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release1()
+
+	// Resizing to 2 must let a second, independent acquire succeed
+	// immediately even though the first slot is still held.
+	p.resize(2)
+
+	acquired := make(chan func(), 1)
+	go func() {
+		release2, err := p.acquire(context.Background())
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+			return
+		}
+		acquired <- release2
+	}()
+
+	select {
+	case release2 := <-acquired:
+		release2()
+	case <-time.After(2 * time.Second):
+		t.Fatal("acquire did not pick up the new concurrency limit")
+	}
+}
+
+func TestWorkerPoolStatsReflectOnlyCurrentGenerationAfterResize(t *testing.T) {
+	p := newWorkerPool(8)
+
+	var oldReleases []func()
+	for i := 0; i < 3; i++ {
+		release, err := p.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		oldReleases = append(oldReleases, release)
+	}
+
+	p.resize(1)
+
+	release, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after resize: %v", err)
+	}
+
+	if stats := p.stats(); stats.Running != 1 {
+		t.Fatalf("expected Running to reflect only the new generation's own slot, got %+v", stats)
+	}
+
+	// Releasing the old generation's slots must not perturb the new
+	// generation's Stats(), since they're no longer the same semaphore.
+	for _, oldRelease := range oldReleases {
+		oldRelease()
+	}
+
+	if stats := p.stats(); stats.Running != 1 {
+		t.Fatalf("expected Running to stay at 1 after releasing old-generation slots, got %+v", stats)
+	}
+
+	release()
+
+	if stats := p.stats(); stats.Running != 0 {
+		t.Fatalf("expected Running to reach 0 once the new generation's slot released, got %+v", stats)
+	}
+}
@@ -0,0 +1,100 @@
+package letsdebug
+
+import "sync"
+
+// Phase identifies which of the three tiers of a scan a Checker runs in.
+type Phase int
+
+const (
+	// PhaseShowstopper checkers run sequentially, first; a fatal error aborts the scan.
+	PhaseShowstopper Phase = iota
+	// PhaseParallel checkers all run together, concurrently, as a single asyncCheckerBlock.
+	PhaseParallel
+	// PhasePost checkers run sequentially after every PhaseParallel checker has completed.
+	PhasePost
+)
+
+// CheckerHandle identifies a previously registered Checker so it can be
+// unregistered or replaced later. It's returned by RegisterChecker rather
+// than looking the checker back up by value, since a third-party Checker
+// may hold a slice, map, or func field and isn't guaranteed comparable.
+type CheckerHandle struct {
+	phase Phase
+	id    uint64
+}
+
+type registryEntry struct {
+	handle  CheckerHandle
+	checker Checker
+}
+
+var (
+	registryMu   sync.Mutex
+	registry     = map[Phase][]registryEntry{}
+	nextHandleID uint64
+)
+
+// RegisterChecker adds c to the scan pipeline in the given phase. This is
+// how third parties can add their own ACME validation checks - a ZeroSSL
+// account check, an internal CA policy check, and so on - without forking
+// letsdebug. It is not safe to call while a scan is in progress.
+func RegisterChecker(c Checker, phase Phase) CheckerHandle {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	nextHandleID++
+	h := CheckerHandle{phase: phase, id: nextHandleID}
+	registry[phase] = append(registry[phase], registryEntry{handle: h, checker: c})
+	return h
+}
+
+// UnregisterChecker removes the checker identified by h, if still registered.
+// It is not safe to call while a scan is in progress.
+func UnregisterChecker(h CheckerHandle) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	cs := registry[h.phase]
+	for i, e := range cs {
+		if e.handle == h {
+			registry[h.phase] = append(cs[:i:i], cs[i+1:]...)
+			return
+		}
+	}
+}
+
+// ReplaceChecker swaps the checker identified by h for with, preserving its
+// phase and position. It is a no-op if h is no longer registered. It is
+// not safe to call while a scan is in progress.
+func ReplaceChecker(h CheckerHandle, with Checker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	cs := registry[h.phase]
+	for i, e := range cs {
+		if e.handle == h {
+			cs[i].checker = with
+			return
+		}
+	}
+}
+
+// registeredCheckers returns a snapshot of the currently registered
+// checkers, grouped by phase.
+func registeredCheckers() (showstoppers []Checker, parallel asyncCheckerBlock, post []Checker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	showstoppers = checkersOf(registry[PhaseShowstopper])
+	parallel = asyncCheckerBlock(checkersOf(registry[PhaseParallel]))
+	post = checkersOf(registry[PhasePost])
+	return
+}
+
+func checkersOf(entries []registryEntry) []Checker {
+	cs := make([]Checker, len(entries))
+	for i, e := range entries {
+		cs[i] = e.checker
+	}
+	return cs
+}
@@ -0,0 +1,43 @@
+package letsdebug
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRegisterReplaceUnregisterChecker(t *testing.T) {
+	withCleanRegistry(t, func() {
+		// fakeChecker holds a func field, so it isn't comparable with ==;
+		// Register/Replace/Unregister must work via handle identity, not
+		// value equality, or they'd panic on a checker like this one.
+		original := fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			return nil, nil
+		}}
+		h := RegisterChecker(original, PhaseParallel)
+
+		showstoppers, parallel, post := registeredCheckers()
+		if len(showstoppers) != 0 || len(post) != 0 || len(parallel) != 1 {
+			t.Fatalf("expected exactly one PhaseParallel checker registered, got %d/%d/%d", len(showstoppers), len(parallel), len(post))
+		}
+
+		replacement := fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			return []Problem{{Name: "replacement"}}, nil
+		}}
+		ReplaceChecker(h, replacement)
+
+		_, parallel, _ = registeredCheckers()
+		if len(parallel) != 1 {
+			t.Fatalf("expected the replacement to occupy the same slot, got %d checkers", len(parallel))
+		}
+		probs, err := parallel[0].Check(context.Background(), newScanContext(), "example.com", HTTP01)
+		if err != nil || len(probs) != 1 || probs[0].Name != "replacement" {
+			t.Fatalf("expected the replacement checker to be in effect, got %+v, %v", probs, err)
+		}
+
+		UnregisterChecker(h)
+		_, parallel, _ = registeredCheckers()
+		if len(parallel) != 0 {
+			t.Fatalf("expected the checker to be gone after UnregisterChecker, got %d", len(parallel))
+		}
+	})
+}
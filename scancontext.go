@@ -0,0 +1,31 @@
+package letsdebug
+
+import "sync"
+
+// ScanContext carries state shared by every Checker run during a single
+// scan - currently a cache of resolver lookups, so that checkers querying
+// the same records don't repeat the same DNS round trips.
+type ScanContext struct {
+	mu            sync.Mutex
+	resolverCache map[string]interface{}
+}
+
+func newScanContext() *ScanContext {
+	return &ScanContext{resolverCache: map[string]interface{}{}}
+}
+
+// ResolverResult returns the cached value for key, if a prior Checker in
+// this scan stored one with SetResolverResult.
+func (sc *ScanContext) ResolverResult(key string) (interface{}, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	v, ok := sc.resolverCache[key]
+	return v, ok
+}
+
+// SetResolverResult stores result under key for later Checkers in this scan to reuse.
+func (sc *ScanContext) SetResolverResult(key string, result interface{}) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.resolverCache[key] = result
+}
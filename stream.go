@@ -0,0 +1,119 @@
+package letsdebug
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// streamingChecker is implemented by checkers that can emit Problems
+// incrementally rather than only returning a completed batch from Check.
+type streamingChecker interface {
+	CheckStream(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod, out chan<- Problem) error
+}
+
+// sendTimeout bounds how long sendProblem will block handing a Problem to a
+// slow consumer. It's deliberately separate from checkerTimeout: a checker
+// finishing near its own deadline shouldn't have that deadline double as
+// "the consumer is abandoned", which would abort the whole scan over a
+// merely slow reader rather than one that's stopped reading entirely.
+var sendTimeout = 5 * time.Second
+
+// errAbandonedConsumer is returned by sendProblem when out isn't drained
+// within sendTimeout.
+var errAbandonedConsumer = errors.New("letsdebug: Problem consumer did not read in time")
+
+// sendProblem delivers p on out, but gives up if ctx is cancelled or out
+// isn't drained within sendTimeout, so an abandoned consumer can't wedge
+// the sending goroutine forever.
+func sendProblem(ctx context.Context, out chan<- Problem, p Problem) error {
+	timer := time.NewTimer(sendTimeout)
+	defer timer.Stop()
+
+	select {
+	case out <- p:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return errAbandonedConsumer
+	}
+}
+
+// CheckStream behaves like CheckWithContext, but delivers each checker's
+// Problems as soon as that checker completes. The Problem channel closes
+// when the scan finishes; the error channel receives exactly one value
+// and then closes.
+func CheckStream(ctx context.Context, domain string, method ValidationMethod) (<-chan Problem, <-chan error) {
+	probsChan := make(chan Problem)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(probsChan)
+		defer close(errChan)
+
+		sc := newScanContext()
+
+		// runSequential applies the same per-checker timeout
+		// asyncCheckerBlock gives its members, so a sequential checker
+		// can't hang the scan forever on an unresponsive resolver. A
+		// recovered panic is delivered via ctx, not checkerCtx, so a
+		// consumer that's merely slow near this checker's own deadline
+		// isn't mistaken for an abandoned one.
+		runSequential := func(c Checker) error {
+			return runSequentialChecker(ctx, c, func(p Problem) {
+				sendProblem(ctx, probsChan, p)
+			}, func(checkerCtx context.Context) error {
+				if streaming, ok := c.(streamingChecker); ok {
+					if err := streaming.CheckStream(checkerCtx, sc, domain, method, probsChan); err != nil && err != errNotApplicable {
+						return err
+					}
+					return nil
+				}
+
+				probs, err := c.Check(checkerCtx, sc, domain, method)
+				for _, p := range probs {
+					// Use ctx, not checkerCtx, so a consumer that's merely
+					// slow near this checker's own deadline isn't mistaken
+					// for an abandoned one.
+					if sendErr := sendProblem(ctx, probsChan, p); sendErr != nil {
+						return sendErr
+					}
+				}
+				if err != nil && err != errNotApplicable {
+					return err
+				}
+				return nil
+			})
+		}
+
+		showstoppers, parallel, post := registeredCheckers()
+
+		for _, c := range showstoppers {
+			if err := runSequential(c); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		if len(parallel) > 0 {
+			if err := ctx.Err(); err != nil {
+				errChan <- err
+				return
+			}
+			if err := parallel.CheckStream(ctx, sc, domain, method, probsChan); err != nil && err != errNotApplicable {
+				errChan <- err
+				return
+			}
+		}
+		for _, c := range post {
+			if err := runSequential(c); err != nil {
+				errChan <- err
+				return
+			}
+		}
+
+		errChan <- nil
+	}()
+
+	return probsChan, errChan
+}
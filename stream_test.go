@@ -0,0 +1,230 @@
+package letsdebug
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// withCleanRegistry runs fn against an empty checker registry, restoring
+// the real one afterwards, so tests can register just the fakes they need
+// without running every built-in checker.
+func withCleanRegistry(t *testing.T, fn func()) {
+	t.Helper()
+
+	registryMu.Lock()
+	saved := registry
+	registry = map[Phase][]registryEntry{}
+	registryMu.Unlock()
+
+	defer func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	}()
+
+	fn()
+}
+
+type fakeStreamingChecker struct {
+	fn func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod, out chan<- Problem) error
+}
+
+func (f fakeStreamingChecker) Check(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+	return nil, nil
+}
+
+func (f fakeStreamingChecker) CheckStream(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod, out chan<- Problem) error {
+	return f.fn(ctx, sc, domain, method, out)
+}
+
+func TestAsyncCheckerBlockCheckStreamNotApplicableDoesNotAbortSiblings(t *testing.T) {
+	notApplicableStarted := make(chan struct{})
+	siblingCancelledEarly := make(chan bool, 1)
+
+	block := asyncCheckerBlock{
+		fakeStreamingChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod, out chan<- Problem) error {
+			<-notApplicableStarted
+			return errNotApplicable
+		}},
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			close(notApplicableStarted)
+			// Give the errNotApplicable return a moment to (wrongly) cancel
+			// gctx before checking whether it did.
+			time.Sleep(50 * time.Millisecond)
+			siblingCancelledEarly <- ctx.Err() != nil
+			return nil, nil
+		}},
+	}
+
+	out := make(chan Problem, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- block.CheckStream(context.Background(), newScanContext(), "example.com", HTTP01, out)
+	}()
+
+	select {
+	case cancelledEarly := <-siblingCancelledEarly:
+		if cancelledEarly {
+			t.Fatal("sibling checker's context was cancelled by a streaming checker's errNotApplicable return")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("sibling checker never ran")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected errNotApplicable from a streaming checker not to abort the block, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("CheckStream did not return")
+	}
+}
+
+func TestAsyncCheckerBlockCheckStreamDeliversPartialResults(t *testing.T) {
+	releaseSlow := make(chan struct{})
+
+	block := asyncCheckerBlock{
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			return []Problem{{Name: "fast"}}, nil
+		}},
+		fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			<-releaseSlow
+			return []Problem{{Name: "slow"}}, nil
+		}},
+	}
+
+	out := make(chan Problem, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- block.CheckStream(context.Background(), newScanContext(), "example.com", HTTP01, out)
+	}()
+
+	select {
+	case p := <-out:
+		if p.Name != "fast" {
+			t.Fatalf("expected the fast checker's Problem first, got %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("did not receive the fast checker's Problem before the slow checker finished")
+	}
+
+	close(releaseSlow)
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCheckStreamClosesProblemChannelOnCompletion(t *testing.T) {
+	withCleanRegistry(t, func() {
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			return []Problem{{Name: "only"}}, nil
+		}}, PhaseParallel)
+
+		probs, errs := CheckStream(context.Background(), "example.com", HTTP01)
+
+		var got []Problem
+		for p := range probs {
+			got = append(got, p)
+		}
+		if len(got) != 1 || got[0].Name != "only" {
+			t.Fatalf("expected exactly one Problem, got %+v", got)
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestCheckStreamAbandonedConsumerDoesNotHang(t *testing.T) {
+	origSendTimeout := sendTimeout
+	sendTimeout = 20 * time.Millisecond
+	defer func() { sendTimeout = origSendTimeout }()
+
+	withCleanRegistry(t, func() {
+		started := make(chan struct{})
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			close(started)
+			return []Problem{{Name: "one"}}, nil
+		}}, PhaseParallel)
+
+		// Deliberately never read from the Problem channel, simulating a
+		// consumer that abandons the scan after starting it.
+		_, errs := CheckStream(context.Background(), "example.com", HTTP01)
+
+		<-started
+
+		select {
+		case err := <-errs:
+			if err != errAbandonedConsumer {
+				t.Fatalf("expected errAbandonedConsumer, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("CheckStream hung delivering to an abandoned consumer instead of giving up after sendTimeout")
+		}
+	})
+}
+
+func TestCheckStreamRecoversShowstopperPanic(t *testing.T) {
+	withCleanRegistry(t, func() {
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			panic("boom")
+		}}, PhaseShowstopper)
+
+		probs, errs := CheckStream(context.Background(), "example.com", HTTP01)
+
+		var got []Problem
+		for p := range probs {
+			got = append(got, p)
+		}
+		if len(got) != 1 || got[0].Severity != SeverityInternal {
+			t.Fatalf("expected one SeverityInternal problem, got %+v", got)
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatalf("expected the recovered panic not to surface as a fatal error, got %v", err)
+		}
+	})
+}
+
+func TestCheckStreamAbortsSiblingsOnFatalError(t *testing.T) {
+	withCleanRegistry(t, func() {
+		boom := errors.New("boom")
+		siblingStarted := make(chan struct{})
+		siblingCtxDone := make(chan struct{})
+
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			// Wait for the sibling to actually start before failing, so its
+			// context is guaranteed to still be live when this error
+			// cancels it. Otherwise the pool could see the shared context
+			// already cancelled and never invoke the sibling's Check at
+			// all, making this test flaky.
+			<-siblingStarted
+			return nil, boom
+		}}, PhaseParallel)
+		RegisterChecker(fakeChecker{fn: func(ctx context.Context, sc *ScanContext, domain string, method ValidationMethod) ([]Problem, error) {
+			close(siblingStarted)
+			<-ctx.Done()
+			close(siblingCtxDone)
+			return nil, ctx.Err()
+		}}, PhaseParallel)
+
+		probs, errs := CheckStream(context.Background(), "example.com", HTTP01)
+		for range probs {
+		}
+
+		if err := <-errs; err != boom {
+			t.Fatalf("expected the fatal error to surface via the error channel, got %v", err)
+		}
+
+		select {
+		case <-siblingCtxDone:
+		case <-time.After(2 * time.Second):
+			t.Fatal("sibling checker's context was never cancelled after the fatal error")
+		}
+	})
+}